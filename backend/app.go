@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+const defaultMaxConcurrentRequests = 4
+
+// App holds the long-lived dependencies shared across requests: a single
+// genai client (instead of dialing a new one per call), the session store,
+// the tool registry, and the rate/concurrency limiters guarding Gemini.
+type App struct {
+	client            *genai.Client
+	sessionStore      SessionStore
+	toolRegistry      *ToolRegistry
+	limiter           *RateLimiter
+	sem               chan struct{}
+	config            *Config
+	maxToolIterations int
+}
+
+// NewApp dials the Gemini client once and wires up the rest of the shared
+// state. The returned App is safe for concurrent use by handlers.
+func NewApp(ctx context.Context, apiKey string) (*App, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, err
+	}
+
+	maxConcurrent := defaultMaxConcurrentRequests
+	if v := os.Getenv("MAX_CONCURRENT_GEMINI_REQUESTS"); v != "" {
+		if parsed, parseErr := strconv.Atoi(v); parseErr == nil && parsed > 0 {
+			maxConcurrent = parsed
+		}
+	}
+
+	config, err := LoadConfig("")
+	if err != nil {
+		return nil, err
+	}
+
+	maxToolIterations := defaultMaxToolIterations
+	if v := os.Getenv("MAX_TOOL_ITERATIONS"); v != "" {
+		if parsed, parseErr := strconv.Atoi(v); parseErr == nil && parsed > 0 {
+			maxToolIterations = parsed
+		}
+	}
+
+	return &App{
+		client:            client,
+		sessionStore:      NewInMemorySessionStore(sessionTTL),
+		toolRegistry:      defaultToolRegistry(),
+		limiter:           NewRateLimiterFromEnv(),
+		sem:               make(chan struct{}, maxConcurrent),
+		config:            config,
+		maxToolIterations: maxToolIterations,
+	}, nil
+}
+
+func (a *App) Close() error {
+	return a.client.Close()
+}
+
+// acquire blocks until a concurrency slot for an in-flight Gemini request
+// is free, or ctx is cancelled first.
+func (a *App) acquire(ctx context.Context) error {
+	select {
+	case a.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *App) release() {
+	<-a.sem
+}
+
+// geminiErrorResponse inspects err for a Gemini 429 and, when present,
+// forwards its Retry-After header to the client instead of collapsing
+// everything into a generic 500.
+func (a *App) geminiErrorResponse(c *gin.Context, err error) {
+	var blocked *BlockedContentError
+	if errors.As(err, &blocked) {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: blocked.Error()})
+		return
+	}
+
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Code == http.StatusTooManyRequests {
+		if retryAfter := gerr.Header.Get("Retry-After"); retryAfter != "" {
+			c.Header("Retry-After", retryAfter)
+		}
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "Gemini API rate limit exceeded"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
+}
+
+func (a *App) handleHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "healthy",
+		"limiter": gin.H{
+			"tracked_clients":      a.limiter.TrackedClients(),
+			"concurrent_in_flight": len(a.sem),
+			"concurrent_capacity":  cap(a.sem),
+		},
+	})
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/google/generative-ai-go/genai"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigPath = "config.yaml"
+
+type SafetySettingConfig struct {
+	Category  string `yaml:"category"`
+	Threshold string `yaml:"threshold"`
+}
+
+type PromptTemplateConfig struct {
+	ID       string `yaml:"id"`
+	Template string `yaml:"template"`
+}
+
+// Config is the server-wide model configuration, loaded once at startup
+// from config.yaml (or CONFIG_PATH). Per-request overrides in ChatRequest
+// are clamped to these values before being applied to a GenerativeModel.
+type Config struct {
+	ModelName         string                 `yaml:"model_name"`
+	SystemInstruction string                 `yaml:"system_instruction"`
+	Temperature       float32                `yaml:"temperature"`
+	TopP              float32                `yaml:"top_p"`
+	TopK              int32                  `yaml:"top_k"`
+	MaxOutputTokens   int32                  `yaml:"max_output_tokens"`
+	StopSequences     []string               `yaml:"stop_sequences"`
+	SafetySettings    []SafetySettingConfig  `yaml:"safety_settings"`
+	PromptTemplates   []PromptTemplateConfig `yaml:"prompt_templates"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		ModelName:       "gemini-1.5-flash",
+		Temperature:     1.0,
+		TopP:            0.95,
+		TopK:            40,
+		MaxOutputTokens: 2048,
+	}
+}
+
+// LoadConfig reads path (or CONFIG_PATH, or defaultConfigPath), falling
+// back to built-in defaults if no config file is present.
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		path = defaultConfigPath
+	}
+
+	cfg := defaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Printf("No config file at %s, using built-in defaults\n", path)
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) templateByID(id string) (*PromptTemplateConfig, bool) {
+	for i := range cfg.PromptTemplates {
+		if cfg.PromptTemplates[i].ID == id {
+			return &cfg.PromptTemplates[i], true
+		}
+	}
+	return nil, false
+}
+
+// RenderTemplate looks up templateID among the configured prompt templates
+// and renders it via text/template with vars.
+func (cfg *Config) RenderTemplate(templateID string, vars map[string]string) (string, error) {
+	tmplCfg, ok := cfg.templateByID(templateID)
+	if !ok {
+		return "", fmt.Errorf("unknown prompt template %q", templateID)
+	}
+
+	tmpl, err := template.New(templateID).Option("missingkey=error").Parse(tmplCfg.Template)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %v", templateID, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %v", templateID, err)
+	}
+	return buf.String(), nil
+}
+
+func (cfg *Config) safetySettings() ([]*genai.SafetySetting, error) {
+	settings := make([]*genai.SafetySetting, 0, len(cfg.SafetySettings))
+	for _, s := range cfg.SafetySettings {
+		category, err := harmCategoryFromString(s.Category)
+		if err != nil {
+			return nil, err
+		}
+		threshold, err := harmBlockThresholdFromString(s.Threshold)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, &genai.SafetySetting{Category: category, Threshold: threshold})
+	}
+	return settings, nil
+}
+
+func harmCategoryFromString(s string) (genai.HarmCategory, error) {
+	switch strings.ToUpper(s) {
+	case "HARASSMENT":
+		return genai.HarmCategoryHarassment, nil
+	case "HATE_SPEECH":
+		return genai.HarmCategoryHateSpeech, nil
+	case "SEXUALLY_EXPLICIT":
+		return genai.HarmCategorySexuallyExplicit, nil
+	case "DANGEROUS_CONTENT":
+		return genai.HarmCategoryDangerousContent, nil
+	default:
+		return 0, fmt.Errorf("unknown safety category %q", s)
+	}
+}
+
+func harmBlockThresholdFromString(s string) (genai.HarmBlockThreshold, error) {
+	switch strings.ToUpper(s) {
+	case "BLOCK_NONE":
+		return genai.HarmBlockNone, nil
+	case "BLOCK_ONLY_HIGH":
+		return genai.HarmBlockOnlyHigh, nil
+	case "BLOCK_MEDIUM_AND_ABOVE":
+		return genai.HarmBlockMediumAndAbove, nil
+	case "BLOCK_LOW_AND_ABOVE":
+		return genai.HarmBlockLowAndAbove, nil
+	default:
+		return 0, fmt.Errorf("unknown safety threshold %q", s)
+	}
+}
+
+// GenerationOverrides carries the per-request values a caller may supply;
+// a nil field means "use the config default".
+type GenerationOverrides struct {
+	Temperature     *float32
+	TopP            *float32
+	TopK            *int32
+	MaxOutputTokens *int32
+}
+
+// clamp returns a copy of o with every field capped to cfg's configured
+// maximum, so a caller can request a smaller budget but never a larger one.
+func (cfg *Config) clamp(o *GenerationOverrides) *GenerationOverrides {
+	if o == nil {
+		return nil
+	}
+	clamped := *o
+
+	if clamped.Temperature != nil && *clamped.Temperature > cfg.Temperature {
+		max := cfg.Temperature
+		clamped.Temperature = &max
+	}
+	if clamped.TopP != nil && *clamped.TopP > cfg.TopP {
+		max := cfg.TopP
+		clamped.TopP = &max
+	}
+	if clamped.TopK != nil && *clamped.TopK > cfg.TopK {
+		max := cfg.TopK
+		clamped.TopK = &max
+	}
+	if clamped.MaxOutputTokens != nil && *clamped.MaxOutputTokens > cfg.MaxOutputTokens {
+		max := cfg.MaxOutputTokens
+		clamped.MaxOutputTokens = &max
+	}
+
+	return &clamped
+}
+
+// Apply configures model with cfg's defaults, then layers any per-request
+// overrides (clamped to cfg's maxima) on top.
+func (cfg *Config) Apply(model *genai.GenerativeModel, overrides *GenerationOverrides) error {
+	temperature, topP, maxOutputTokens := cfg.Temperature, cfg.TopP, cfg.MaxOutputTokens
+	topK := cfg.TopK
+
+	if overrides = cfg.clamp(overrides); overrides != nil {
+		if overrides.Temperature != nil {
+			temperature = *overrides.Temperature
+		}
+		if overrides.TopP != nil {
+			topP = *overrides.TopP
+		}
+		if overrides.TopK != nil {
+			topK = *overrides.TopK
+		}
+		if overrides.MaxOutputTokens != nil {
+			maxOutputTokens = *overrides.MaxOutputTokens
+		}
+	}
+
+	model.Temperature = &temperature
+	model.TopP = &topP
+	model.TopK = &topK
+	model.MaxOutputTokens = &maxOutputTokens
+	model.StopSequences = cfg.StopSequences
+
+	safetySettings, err := cfg.safetySettings()
+	if err != nil {
+		return err
+	}
+	model.SafetySettings = safetySettings
+
+	if cfg.SystemInstruction != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(cfg.SystemInstruction)}}
+	}
+
+	return nil
+}
+
+// BlockedContentError indicates the model refused to answer because a
+// safety filter tripped, so handlers can surface it as a 4xx rather than a
+// generic 500.
+type BlockedContentError struct {
+	Reason string
+}
+
+func (e *BlockedContentError) Error() string {
+	return fmt.Sprintf("content blocked: %s", e.Reason)
+}
+
+func checkBlockReason(resp *genai.GenerateContentResponse) error {
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != genai.BlockReasonUnspecified {
+		return &BlockedContentError{Reason: resp.PromptFeedback.BlockReason.String()}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func float32p(f float32) *float32 { return &f }
+func int32p(i int32) *int32       { return &i }
+
+func TestConfigClampCapsAboveMaxima(t *testing.T) {
+	cfg := &Config{Temperature: 0.5, TopP: 0.5, TopK: 10, MaxOutputTokens: 100}
+
+	clamped := cfg.clamp(&GenerationOverrides{
+		Temperature:     float32p(2.0),
+		TopP:            float32p(0.9),
+		TopK:            int32p(50),
+		MaxOutputTokens: int32p(1000),
+	})
+
+	if *clamped.Temperature != cfg.Temperature {
+		t.Fatalf("Temperature = %v, want %v", *clamped.Temperature, cfg.Temperature)
+	}
+	if *clamped.TopP != cfg.TopP {
+		t.Fatalf("TopP = %v, want %v", *clamped.TopP, cfg.TopP)
+	}
+	if *clamped.TopK != cfg.TopK {
+		t.Fatalf("TopK = %v, want %v", *clamped.TopK, cfg.TopK)
+	}
+	if *clamped.MaxOutputTokens != cfg.MaxOutputTokens {
+		t.Fatalf("MaxOutputTokens = %v, want %v", *clamped.MaxOutputTokens, cfg.MaxOutputTokens)
+	}
+}
+
+func TestConfigClampLeavesValuesBelowMaximaUntouched(t *testing.T) {
+	cfg := &Config{Temperature: 1.0, TopP: 0.95, TopK: 40, MaxOutputTokens: 2048}
+
+	requested := float32(0.2)
+	clamped := cfg.clamp(&GenerationOverrides{Temperature: &requested})
+
+	if *clamped.Temperature != requested {
+		t.Fatalf("Temperature = %v, want %v (should not be clamped upward)", *clamped.Temperature, requested)
+	}
+}
+
+func TestConfigClampNilIsNil(t *testing.T) {
+	cfg := &Config{Temperature: 1.0}
+	if got := cfg.clamp(nil); got != nil {
+		t.Fatalf("clamp(nil) = %v, want nil", got)
+	}
+}
+
+func TestConfigApplyUsesDefaultsWithoutOverrides(t *testing.T) {
+	cfg := &Config{
+		ModelName:       "gemini-1.5-flash",
+		Temperature:     0.7,
+		TopP:            0.8,
+		TopK:            20,
+		MaxOutputTokens: 512,
+	}
+
+	model := &genai.GenerativeModel{}
+	if err := cfg.Apply(model, nil); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	if *model.Temperature != cfg.Temperature {
+		t.Fatalf("Temperature = %v, want %v", *model.Temperature, cfg.Temperature)
+	}
+	if *model.MaxOutputTokens != cfg.MaxOutputTokens {
+		t.Fatalf("MaxOutputTokens = %v, want %v", *model.MaxOutputTokens, cfg.MaxOutputTokens)
+	}
+}
+
+func TestConfigApplyClampsOverridesBeforeUse(t *testing.T) {
+	cfg := &Config{Temperature: 0.5, TopP: 0.5, TopK: 10, MaxOutputTokens: 100}
+	model := &genai.GenerativeModel{}
+
+	overrides := &GenerationOverrides{Temperature: float32p(5.0)}
+	if err := cfg.Apply(model, overrides); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	if *model.Temperature != cfg.Temperature {
+		t.Fatalf("Temperature = %v, want clamped value %v", *model.Temperature, cfg.Temperature)
+	}
+}
+
+func TestConfigRenderTemplate(t *testing.T) {
+	cfg := &Config{
+		PromptTemplates: []PromptTemplateConfig{
+			{ID: "summarize", Template: "Summarize in {{.sentences}} sentences:\n{{.text}}"},
+		},
+	}
+
+	out, err := cfg.RenderTemplate("summarize", map[string]string{"sentences": "2", "text": "hello"})
+	if err != nil {
+		t.Fatalf("RenderTemplate returned error: %v", err)
+	}
+	if want := "Summarize in 2 sentences:\nhello"; out != want {
+		t.Fatalf("RenderTemplate() = %q, want %q", out, want)
+	}
+}
+
+func TestConfigRenderTemplateUnknownID(t *testing.T) {
+	cfg := &Config{}
+	if _, err := cfg.RenderTemplate("missing", nil); err == nil {
+		t.Fatal("expected an error for an unknown template id")
+	}
+}
+
+func TestCheckBlockReason(t *testing.T) {
+	blocked := &genai.GenerateContentResponse{
+		PromptFeedback: &genai.PromptFeedback{BlockReason: genai.BlockReasonSafety},
+	}
+	if err := checkBlockReason(blocked); err == nil {
+		t.Fatal("expected an error when BlockReason is set")
+	}
+
+	clean := &genai.GenerateContentResponse{
+		PromptFeedback: &genai.PromptFeedback{BlockReason: genai.BlockReasonUnspecified},
+	}
+	if err := checkBlockReason(clean); err != nil {
+		t.Fatalf("expected no error when BlockReason is unspecified, got %v", err)
+	}
+}
@@ -6,15 +6,30 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/generative-ai-go/genai"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/option"
 )
 
+// sessionTTL is how long an idle session is kept before eviction.
+const sessionTTL = 30 * time.Minute
+
 type ChatRequest struct {
-	Message string `json:"message" binding:"required,min=1,max=1000"`
+	Message   string `json:"message" binding:"max=1000"`
+	SessionID string `json:"session_id,omitempty"`
+
+	// TemplateID renders a named prompt template (configured server-side)
+	// with TemplateVars and uses the result as the message.
+	TemplateID   string            `json:"template_id,omitempty"`
+	TemplateVars map[string]string `json:"template_vars,omitempty"`
+
+	// Generation overrides, clamped to the admin-configured maxima.
+	Temperature     *float32 `json:"temperature,omitempty"`
+	TopP            *float32 `json:"top_p,omitempty"`
+	TopK            *int32   `json:"top_k,omitempty"`
+	MaxOutputTokens *int32   `json:"max_output_tokens,omitempty"`
 }
 
 type ChatResponse struct {
@@ -26,8 +41,7 @@ type ErrorResponse struct {
 }
 
 func main() {
-	err := godotenv.Load()
-	if err != nil {
+	if err := godotenv.Load(); err != nil {
 		fmt.Println("Warning: .env file not found, using system environment variables")
 	}
 
@@ -37,7 +51,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	app, err := NewApp(context.Background(), apiKey)
+	if err != nil {
+		fmt.Printf("Failed to create Gemini client: %v\n", err)
+		os.Exit(1)
+	}
+	defer app.Close()
+
 	router := gin.Default()
+	router.MaxMultipartMemory = maxImageBytes
 
 	router.Use(func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
@@ -55,11 +77,17 @@ func main() {
 		c.Next()
 	})
 
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
-	})
+	router.GET("/health", app.handleHealth)
 
-	router.POST("/api/chat", handleChat)
+	api := router.Group("/api")
+	api.Use(app.limiter.Middleware())
+
+	api.POST("/chat", app.handleChat())
+	api.POST("/chat/stream", app.handleChatStream)
+	api.POST("/chat/multimodal", app.handleChatMultimodal)
+	api.POST("/chat/structured", app.handleChatStructured)
+	api.POST("/sessions", handleCreateSession(app.sessionStore))
+	api.DELETE("/sessions/:id", handleDeleteSession(app.sessionStore))
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -70,58 +98,213 @@ func main() {
 	router.Run(":" + port)
 }
 
-func handleChat(c *gin.Context) {
-	var req ChatRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("JSON binding error: %v\n", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request format or message too long"})
-		return
+func (a *App) handleChat() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ChatRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			fmt.Printf("JSON binding error: %v\n", err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request format or message too long"})
+			return
+		}
+
+		if req.TemplateID != "" {
+			rendered, err := a.config.RenderTemplate(req.TemplateID, req.TemplateVars)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+				return
+			}
+			req.Message = rendered
+		}
+
+		req.Message = strings.TrimSpace(req.Message)
+		if req.Message == "" {
+			fmt.Printf("Empty message received\n")
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Message cannot be empty"})
+			return
+		}
+
+		overrides := &GenerationOverrides{
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			TopK:            req.TopK,
+			MaxOutputTokens: req.MaxOutputTokens,
+		}
+
+		fmt.Printf("Processing message: %s\n", req.Message)
+
+		var response string
+		var err error
+		if req.SessionID != "" {
+			response, err = a.callGeminiAPIWithSession(c.Request.Context(), req.SessionID, req.Message, overrides)
+		} else {
+			response, err = a.callGeminiAPI(c.Request.Context(), req.Message, overrides)
+		}
+		if err != nil {
+			fmt.Printf("API Error: %v\n", err)
+			a.geminiErrorResponse(c, err)
+			return
+		}
+
+		fmt.Printf("API response received successfully\n")
+		c.JSON(http.StatusOK, ChatResponse{Response: response})
+	}
+}
+
+func (a *App) callGeminiAPI(ctx context.Context, message string, overrides *GenerationOverrides) (string, error) {
+	if err := a.acquire(ctx); err != nil {
+		return "", err
 	}
+	defer a.release()
 
-	req.Message = strings.TrimSpace(req.Message)
-	if req.Message == "" {
-		fmt.Printf("Empty message received\n")
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Message cannot be empty"})
-		return
+	fmt.Printf("Calling Gemini API with message length: %d\n", len(message))
+
+	model := a.client.GenerativeModel(a.config.ModelName)
+	if err := a.config.Apply(model, overrides); err != nil {
+		return "", err
+	}
+	if a.toolRegistry != nil && a.toolRegistry.Len() > 0 {
+		model.Tools = a.toolRegistry.AsGenaiTools()
 	}
 
-	fmt.Printf("Processing message: %s\n", req.Message)
+	cs := model.StartChat()
 
-	response, err := callGeminiAPI(req.Message)
+	fmt.Printf("Sending request to Gemini API...\n")
+	resp, err := cs.SendMessage(ctx, genai.Text(message))
 	if err != nil {
-		fmt.Printf("API Error: %v\n", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
-		return
+		fmt.Printf("Gemini API request failed: %v\n", err)
+		return "", fmt.Errorf("failed to generate content: %w", err)
+	}
+	if err := checkBlockReason(resp); err != nil {
+		return "", err
 	}
 
-	fmt.Printf("API response received successfully\n")
-	c.JSON(http.StatusOK, ChatResponse{Response: response})
-}
+	for i := 0; i < a.maxToolIterations; i++ {
+		calls := extractFunctionCalls(resp)
+		if len(calls) == 0 {
+			break
+		}
 
-func callGeminiAPI(message string) (string, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("API key not configured")
+		// Strip empty text parts before resubmitting, or the API rejects
+		// the round trip with "empty text parameter".
+		stripEmptyTextParts(cs)
+
+		responseParts := make([]genai.Part, 0, len(calls))
+		for _, call := range calls {
+			fmt.Printf("Invoking tool %q\n", call.Name)
+			result, invokeErr := a.toolRegistry.Invoke(ctx, call.Name, call.Args)
+			if invokeErr != nil {
+				fmt.Printf("Tool %q failed: %v\n", call.Name, invokeErr)
+				result = map[string]any{"error": invokeErr.Error()}
+			}
+			responseParts = append(responseParts, genai.FunctionResponse{
+				Name:     call.Name,
+				Response: map[string]any{"result": result},
+			})
+		}
+
+		resp, err = cs.SendMessage(ctx, responseParts...)
+		if err != nil {
+			fmt.Printf("Gemini API request failed: %v\n", err)
+			return "", fmt.Errorf("failed to generate content: %w", err)
+		}
+		if err := checkBlockReason(resp); err != nil {
+			return "", err
+		}
 	}
 
-	fmt.Printf("Calling Gemini API with message length: %d\n", len(message))
+	if len(resp.Candidates) == 0 ||
+		len(resp.Candidates[0].Content.Parts) == 0 {
+		fmt.Printf("Empty response from Gemini API\n")
+		return "", fmt.Errorf("no response from AI")
+	}
 
-	ctx := context.Background()
+	responseText := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
+	fmt.Printf("Gemini API response length: %d\n", len(responseText))
+	return responseText, nil
+}
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+// callGeminiAPIWithSession replays the stored history for sessionID through
+// model.StartChat(), sends message, and persists the updated history back
+// to the store so the next call in the same session continues the
+// conversation.
+func (a *App) callGeminiAPIWithSession(ctx context.Context, sessionID, message string, overrides *GenerationOverrides) (string, error) {
+	sess, err := a.sessionStore.Get(sessionID)
 	if err != nil {
-		fmt.Printf("Failed to create Gemini client: %v\n", err)
-		return "", fmt.Errorf("failed to create client: %v", err)
+		return "", fmt.Errorf("unknown session: %v", err)
 	}
-	defer client.Close()
 
-	model := client.GenerativeModel("gemini-1.5-flash")
+	// Hold the session's lock across the whole replay-send-persist cycle so
+	// two concurrent requests for the same session_id serialize instead of
+	// racing on History.
+	sess.Lock()
+	defer sess.Unlock()
 
-	fmt.Printf("Sending request to Gemini API...\n")
-	resp, err := model.GenerateContent(ctx, genai.Text(message))
+	if err := a.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer a.release()
+
+	model := a.client.GenerativeModel(a.config.ModelName)
+	if err := a.config.Apply(model, overrides); err != nil {
+		return "", err
+	}
+	if a.toolRegistry != nil && a.toolRegistry.Len() > 0 {
+		model.Tools = a.toolRegistry.AsGenaiTools()
+	}
+	if sess.SystemInstruction != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(sess.SystemInstruction)}}
+	}
+
+	cs := model.StartChat()
+	cs.History = sess.History
+
+	fmt.Printf("Sending session message (session=%s, history=%d turns)...\n", sessionID, len(cs.History))
+	resp, err := cs.SendMessage(ctx, genai.Text(message))
 	if err != nil {
 		fmt.Printf("Gemini API request failed: %v\n", err)
-		return "", fmt.Errorf("failed to generate content: %v", err)
+		return "", fmt.Errorf("failed to generate content: %w", err)
+	}
+	if err := checkBlockReason(resp); err != nil {
+		return "", err
+	}
+
+	for i := 0; i < a.maxToolIterations; i++ {
+		calls := extractFunctionCalls(resp)
+		if len(calls) == 0 {
+			break
+		}
+
+		// Strip empty text parts before resubmitting, or the API rejects
+		// the round trip with "empty text parameter".
+		stripEmptyTextParts(cs)
+
+		responseParts := make([]genai.Part, 0, len(calls))
+		for _, call := range calls {
+			fmt.Printf("Invoking tool %q\n", call.Name)
+			result, invokeErr := a.toolRegistry.Invoke(ctx, call.Name, call.Args)
+			if invokeErr != nil {
+				fmt.Printf("Tool %q failed: %v\n", call.Name, invokeErr)
+				result = map[string]any{"error": invokeErr.Error()}
+			}
+			responseParts = append(responseParts, genai.FunctionResponse{
+				Name:     call.Name,
+				Response: map[string]any{"result": result},
+			})
+		}
+
+		resp, err = cs.SendMessage(ctx, responseParts...)
+		if err != nil {
+			fmt.Printf("Gemini API request failed: %v\n", err)
+			return "", fmt.Errorf("failed to generate content: %w", err)
+		}
+		if err := checkBlockReason(resp); err != nil {
+			return "", err
+		}
+	}
+
+	sess.History = cs.History
+	if err := a.sessionStore.Save(sess); err != nil {
+		fmt.Printf("Failed to persist session history: %v\n", err)
 	}
 
 	if len(resp.Candidates) == 0 ||
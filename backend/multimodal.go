@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+)
+
+// maxImageBytes is the per-file size cap enforced on multimodal uploads.
+const maxImageBytes = 4 << 20 // 4MB
+
+var allowedImageMIMETypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+}
+
+// handleChatMultimodal accepts a text message alongside one or more images
+// and forwards them to a vision-capable Gemini model.
+func (a *App) handleChatMultimodal(c *gin.Context) {
+	message := strings.TrimSpace(c.PostForm("message"))
+	if message == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Message cannot be empty"})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid multipart form"})
+		return
+	}
+
+	files := form.File["image"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "At least one image is required"})
+		return
+	}
+
+	var parts []genai.Part
+	for _, fh := range files {
+		if fh.Size > maxImageBytes {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Image %q exceeds the 4MB size limit", fh.Filename)})
+			return
+		}
+
+		mimeType := fh.Header.Get("Content-Type")
+		if !allowedImageMIMETypes[mimeType] {
+			c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Error: fmt.Sprintf("Unsupported image type %q", mimeType)})
+			return
+		}
+
+		file, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read uploaded image"})
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read uploaded image"})
+			return
+		}
+
+		parts = append(parts, genai.ImageData(imageFormat(mimeType), data))
+	}
+	parts = append(parts, genai.Text(message))
+
+	ctx := c.Request.Context()
+
+	if err := a.acquire(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
+		return
+	}
+	defer a.release()
+
+	// Vision-capable model regardless of the configured default, since the
+	// default text model may not support image inputs.
+	model := a.client.GenerativeModel("gemini-1.5-pro")
+	if err := a.config.Apply(model, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
+		return
+	}
+
+	resp, err := model.GenerateContent(ctx, parts...)
+	if err != nil {
+		fmt.Printf("Gemini API request failed: %v\n", err)
+		a.geminiErrorResponse(c, err)
+		return
+	}
+
+	if blockErr := checkBlockReason(resp); blockErr != nil {
+		a.geminiErrorResponse(c, blockErr)
+		return
+	}
+
+	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
+		return
+	}
+
+	responseText := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
+	c.JSON(http.StatusOK, ChatResponse{Response: responseText})
+}
+
+// imageFormat strips the "image/" prefix so it can be passed as the format
+// argument to genai.ImageData (e.g. "png", "jpeg", "webp").
+func imageFormat(mimeType string) string {
+	return strings.TrimPrefix(mimeType, "image/")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRateLimitRPS   = 1.0
+	defaultRateLimitBurst = 5
+)
+
+// RateLimiter hands out a token-bucket limiter per client key so one client
+// can't starve the others. Keys are the bearer token when present,
+// otherwise the remote IP.
+type RateLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	rps      rate.Limit
+	burst    int
+}
+
+// NewRateLimiterFromEnv builds a limiter using RATE_LIMIT_RPS and
+// RATE_LIMIT_BURST, falling back to conservative defaults.
+func NewRateLimiterFromEnv() *RateLimiter {
+	rps := defaultRateLimitRPS
+	if v := os.Getenv("RATE_LIMIT_RPS"); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			rps = parsed
+		}
+	}
+
+	burst := defaultRateLimitBurst
+	if v := os.Getenv("RATE_LIMIT_BURST"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			burst = parsed
+		}
+	}
+
+	return &RateLimiter{
+		limiters: make(map[string]*rate.Limiter),
+		rps:      rate.Limit(rps),
+		burst:    burst,
+	}
+}
+
+func (r *RateLimiter) limiterFor(key string) *rate.Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.limiters[key]
+	if !ok {
+		l = rate.NewLimiter(r.rps, r.burst)
+		r.limiters[key] = l
+	}
+	return l
+}
+
+// TrackedClients reports how many distinct client keys currently have a
+// limiter allocated; surfaced on /health.
+func (r *RateLimiter) TrackedClients() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.limiters)
+}
+
+func clientKey(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return "token:" + strings.TrimPrefix(auth, "Bearer ")
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware rejects requests over the configured rps/burst with a 429 and
+// a Retry-After header computed from the limiter's own reservation delay.
+func (r *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter := r.limiterFor(clientKey(c))
+
+		reservation := limiter.Reserve()
+		if !reservation.OK() {
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "rate limit exceeded"})
+			c.Abort()
+			return
+		}
+
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			c.Header("Retry-After", fmt.Sprintf("%.0f", delay.Seconds()))
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "rate limit exceeded"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
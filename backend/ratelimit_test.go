@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+func TestClientKeyPrefersBearerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.RemoteAddr = "10.0.0.1:1234"
+	c.Request = req
+
+	if got, want := clientKey(c), "token:secret-token"; got != want {
+		t.Fatalf("clientKey() = %q, want %q", got, want)
+	}
+}
+
+func TestClientKeyFallsBackToIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	c.Request = req
+
+	if got, want := clientKey(c), "ip:10.0.0.1"; got != want {
+		t.Fatalf("clientKey() = %q, want %q", got, want)
+	}
+}
+
+func TestRateLimiterLimiterForIsStablePerKey(t *testing.T) {
+	r := &RateLimiter{limiters: make(map[string]*rate.Limiter), rps: 1, burst: 1}
+	first := r.limiterFor("ip:1.2.3.4")
+	second := r.limiterFor("ip:1.2.3.4")
+	if first != second {
+		t.Fatal("limiterFor returned a different limiter for the same key")
+	}
+
+	other := r.limiterFor("ip:5.6.7.8")
+	if other == first {
+		t.Fatal("limiterFor returned the same limiter for different keys")
+	}
+}
+
+func TestRateLimiterMiddlewareRejectsOverBurst(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	limiter := &RateLimiter{limiters: make(map[string]*rate.Limiter), rps: 0, burst: 1}
+	router.Use(limiter.Middleware())
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	makeRequest := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.RemoteAddr = "1.2.3.4:1234"
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := makeRequest(); code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", code, http.StatusOK)
+	}
+	if code := makeRequest(); code != http.StatusTooManyRequests {
+		t.Fatalf("second request (over burst) status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
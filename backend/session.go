@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+	"github.com/google/uuid"
+)
+
+// ErrSessionNotFound is returned by a SessionStore when the requested
+// session does not exist or has already been evicted.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session holds the replayable state of a single multi-turn conversation.
+// Its own mutex must be held across a full read-modify-write cycle (replay
+// history, call the model, persist the new history) so two concurrent
+// requests against the same session_id serialize instead of racing on
+// History.
+type Session struct {
+	ID                string
+	History           []*genai.Content
+	SystemInstruction string
+	CreatedAt         time.Time
+	LastUsedAt        time.Time
+
+	mu sync.Mutex
+}
+
+// Lock and Unlock guard a session's fields across a read-modify-write
+// cycle. Callers must Lock after Get and Unlock after Save.
+func (s *Session) Lock()   { s.mu.Lock() }
+func (s *Session) Unlock() { s.mu.Unlock() }
+
+// SessionStore persists chat sessions between requests. The in-memory
+// implementation below is the default; a Redis/Postgres backend can be
+// dropped in by satisfying the same interface.
+type SessionStore interface {
+	Create(systemInstruction string) (*Session, error)
+	Get(id string) (*Session, error)
+	Save(sess *Session) error
+	Delete(id string) error
+}
+
+// InMemorySessionStore keeps sessions in a map and evicts any session that
+// has been idle for longer than ttl. It is safe for concurrent use.
+type InMemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	ttl      time.Duration
+}
+
+// NewInMemorySessionStore creates a store and starts its background
+// eviction loop. ttl is the idle duration after which a session is dropped.
+func NewInMemorySessionStore(ttl time.Duration) *InMemorySessionStore {
+	store := &InMemorySessionStore{
+		sessions: make(map[string]*Session),
+		ttl:      ttl,
+	}
+	go store.evictLoop()
+	return store
+}
+
+func (s *InMemorySessionStore) Create(systemInstruction string) (*Session, error) {
+	sess := &Session{
+		ID:                uuid.NewString(),
+		SystemInstruction: systemInstruction,
+		CreatedAt:         time.Now(),
+		LastUsedAt:        time.Now(),
+	}
+
+	s.mu.Lock()
+	s.sessions[sess.ID] = sess
+	s.mu.Unlock()
+
+	return sess, nil
+}
+
+func (s *InMemorySessionStore) Get(id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	// Bump LastUsedAt here, under the store lock, rather than waiting for
+	// Save: otherwise a session can look idle to evictExpired in the window
+	// between a caller's Get and its subsequent sess.Lock(), and get evicted
+	// out from under a request that's about to use it.
+	sess.LastUsedAt = time.Now()
+	return sess, nil
+}
+
+func (s *InMemorySessionStore) Save(sess *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess.LastUsedAt = time.Now()
+	s.sessions[sess.ID] = sess
+	return nil
+}
+
+func (s *InMemorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *InMemorySessionStore) evictLoop() {
+	interval := s.ttl / 2
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.evictExpired()
+	}
+}
+
+func (s *InMemorySessionStore) evictExpired() {
+	cutoff := time.Now().Add(-s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.sessions {
+		if !sess.LastUsedAt.Before(cutoff) {
+			continue
+		}
+		// Skip a session that's mid-request rather than evicting out from
+		// under its in-flight read-modify-write; it'll be retried next tick.
+		if !sess.mu.TryLock() {
+			continue
+		}
+		delete(s.sessions, id)
+		sess.mu.Unlock()
+	}
+}
+
+type CreateSessionRequest struct {
+	SystemInstruction string `json:"system_instruction,omitempty"`
+}
+
+type CreateSessionResponse struct {
+	SessionID string `json:"session_id"`
+}
+
+func handleCreateSession(store SessionStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateSessionRequest
+		// The body is entirely optional, so ignore bind errors on an empty request.
+		_ = c.ShouldBindJSON(&req)
+
+		sess, err := store.Create(req.SystemInstruction)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create session"})
+			return
+		}
+
+		c.JSON(http.StatusOK, CreateSessionResponse{SessionID: sess.ID})
+	}
+}
+
+func handleDeleteSession(store SessionStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if err := store.Delete(id); err != nil {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Session not found"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
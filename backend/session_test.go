@@ -0,0 +1,151 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInMemorySessionStoreCreateGetDelete(t *testing.T) {
+	store := NewInMemorySessionStore(time.Minute)
+
+	sess, err := store.Create("be nice")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if sess.SystemInstruction != "be nice" {
+		t.Fatalf("SystemInstruction = %q, want %q", sess.SystemInstruction, "be nice")
+	}
+
+	got, err := store.Get(sess.ID)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if got.ID != sess.ID {
+		t.Fatalf("Get returned session %q, want %q", got.ID, sess.ID)
+	}
+
+	if err := store.Delete(sess.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := store.Get(sess.ID); err != ErrSessionNotFound {
+		t.Fatalf("Get after Delete returned %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestInMemorySessionStoreGetUnknown(t *testing.T) {
+	store := NewInMemorySessionStore(time.Minute)
+	if _, err := store.Get("does-not-exist"); err != ErrSessionNotFound {
+		t.Fatalf("Get returned %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestInMemorySessionStoreEvictsIdleSessions(t *testing.T) {
+	store := NewInMemorySessionStore(20 * time.Millisecond)
+
+	sess, err := store.Create("")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		store.evictExpired()
+		// Look in the map directly: Get refreshes LastUsedAt and would keep
+		// the session from ever looking idle.
+		store.mu.Lock()
+		_, present := store.sessions[sess.ID]
+		store.mu.Unlock()
+		if !present {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session was not evicted after its ttl elapsed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestInMemorySessionStoreSkipsEvictingLockedSession(t *testing.T) {
+	store := NewInMemorySessionStore(time.Millisecond)
+
+	sess, err := store.Create("")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	sess.Lock()
+	defer sess.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+	store.evictExpired()
+
+	if _, err := store.Get(sess.ID); err != nil {
+		t.Fatalf("locked session was evicted mid-use: %v", err)
+	}
+}
+
+func TestInMemorySessionStoreGetRefreshesLastUsedBeforeCallerLocks(t *testing.T) {
+	// Built without the background evictLoop, so only the explicit
+	// evictExpired call below can evict the session.
+	store := &InMemorySessionStore{sessions: make(map[string]*Session), ttl: 10 * time.Millisecond}
+
+	sess, err := store.Create("")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	// Let the session sit long enough that, without Get refreshing
+	// LastUsedAt, it would already look idle to evictExpired.
+	time.Sleep(15 * time.Millisecond)
+
+	// Simulates callGeminiAPIWithSession's Get-then-Lock gap: a caller that
+	// has fetched the session but has not yet called sess.Lock().
+	got, err := store.Get(sess.ID)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+
+	store.evictExpired()
+
+	if _, err := store.Get(got.ID); err != nil {
+		t.Fatalf("session was evicted in the gap between Get and Lock: %v", err)
+	}
+}
+
+func TestSessionLockSerializesHistoryUpdates(t *testing.T) {
+	store := NewInMemorySessionStore(time.Minute)
+	sess, err := store.Create("")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+
+			s, err := store.Get(sess.ID)
+			if err != nil {
+				t.Errorf("Get returned error: %v", err)
+				return
+			}
+
+			s.Lock()
+			s.History = append(s.History, nil)
+			_ = store.Save(s)
+			s.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	final, err := store.Get(sess.ID)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if len(final.History) != n {
+		t.Fatalf("History has %d entries, want %d (lost update under concurrency)", len(final.History), n)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// handleChatStream streams the model's response back to the client as
+// Server-Sent Events, one `data:` frame per response part, terminated by an
+// `event: done` frame, or by an `event: error` frame if generation fails or
+// is blocked.
+func (a *App) handleChatStream(c *gin.Context) {
+	var req ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("JSON binding error: %v\n", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request format or message too long"})
+		return
+	}
+
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Message cannot be empty"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := a.acquire(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
+		return
+	}
+	defer a.release()
+
+	model := a.client.GenerativeModel(a.config.ModelName)
+	if err := a.config.Apply(model, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	iter := model.GenerateContentStream(ctx, genai.Text(req.Message))
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				// Client disconnected; nothing left to write.
+				return
+			}
+			fmt.Printf("Gemini stream error: %v\n", err)
+			fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", "failed to generate content")
+			c.Writer.Flush()
+			return
+		}
+
+		if blockErr := checkBlockReason(resp); blockErr != nil {
+			fmt.Printf("Gemini stream blocked: %v\n", blockErr)
+			fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", blockErr.Error())
+			c.Writer.Flush()
+			return
+		}
+
+		for _, cand := range resp.Candidates {
+			if cand.Content == nil {
+				continue
+			}
+			for _, part := range cand.Content.Parts {
+				text := fmt.Sprintf("%v", part)
+				text = strings.ReplaceAll(text, "\n", "\\n")
+				fmt.Fprintf(c.Writer, "data: %s\n\n", text)
+			}
+		}
+		c.Writer.Flush()
+	}
+
+	fmt.Fprint(c.Writer, "event: done\ndata: {}\n\n")
+	c.Writer.Flush()
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+)
+
+// structuredSchemaSupportedKeys lists the JSON Schema keywords this
+// converter understands. Anything else (oneOf, $ref, patternProperties,
+// ...) is rejected with a clear error rather than silently ignored.
+var structuredSchemaSupportedKeys = map[string]bool{
+	"type": true, "properties": true, "required": true,
+	"items": true, "enum": true, "description": true,
+}
+
+type StructuredChatRequest struct {
+	Prompt string         `json:"prompt" binding:"required,min=1,max=1000"`
+	Schema map[string]any `json:"schema" binding:"required"`
+}
+
+// handleChatStructured asks the model to produce JSON conforming to a
+// caller-supplied schema, then validates the response before returning it.
+func (a *App) handleChatStructured(c *gin.Context) {
+	var req StructuredChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request format"})
+		return
+	}
+
+	req.Prompt = strings.TrimSpace(req.Prompt)
+	if req.Prompt == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Prompt cannot be empty"})
+		return
+	}
+
+	genaiSchema, err := jsonSchemaToGenaiSchema(req.Schema)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Unsupported schema: %v", err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := a.acquire(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
+		return
+	}
+	defer a.release()
+
+	model := a.client.GenerativeModel(a.config.ModelName)
+	if err := a.config.Apply(model, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
+		return
+	}
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = genaiSchema
+
+	resp, err := model.GenerateContent(ctx, genai.Text(req.Prompt))
+	if err != nil {
+		fmt.Printf("Gemini API request failed: %v\n", err)
+		a.geminiErrorResponse(c, err)
+		return
+	}
+
+	if blockErr := checkBlockReason(resp); blockErr != nil {
+		a.geminiErrorResponse(c, blockErr)
+		return
+	}
+
+	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get AI response"})
+		return
+	}
+
+	raw := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
+
+	var parsed any
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "model did not return valid JSON", "raw": raw})
+		return
+	}
+
+	if err := validateAgainstSchema(parsed, req.Schema); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("response failed schema validation: %v", err), "raw": raw})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": parsed})
+}
+
+// jsonSchemaToGenaiSchema converts the supported subset of JSON Schema
+// (type, properties, required, items, enum, description) into a
+// *genai.Schema suitable for GenerativeModel.ResponseSchema.
+func jsonSchemaToGenaiSchema(raw map[string]any) (*genai.Schema, error) {
+	for key := range raw {
+		if !structuredSchemaSupportedKeys[key] {
+			return nil, fmt.Errorf("unsupported keyword %q", key)
+		}
+	}
+
+	typeStr, _ := raw["type"].(string)
+	genaiType, err := jsonSchemaTypeToGenaiType(typeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &genai.Schema{Type: genaiType}
+
+	if desc, ok := raw["description"].(string); ok {
+		schema.Description = desc
+	}
+
+	if rawEnum, ok := raw["enum"].([]any); ok {
+		enum := make([]string, 0, len(rawEnum))
+		for _, v := range rawEnum {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("enum values must be strings")
+			}
+			enum = append(enum, s)
+		}
+		schema.Enum = enum
+	}
+
+	if rawProps, ok := raw["properties"].(map[string]any); ok {
+		props := make(map[string]*genai.Schema, len(rawProps))
+		for name, rawProp := range rawProps {
+			propMap, ok := rawProp.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("properties.%s must be an object", name)
+			}
+			propSchema, err := jsonSchemaToGenaiSchema(propMap)
+			if err != nil {
+				return nil, fmt.Errorf("properties.%s: %w", name, err)
+			}
+			props[name] = propSchema
+		}
+		schema.Properties = props
+	}
+
+	if rawRequired, ok := raw["required"].([]any); ok {
+		required := make([]string, 0, len(rawRequired))
+		for _, v := range rawRequired {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("required entries must be strings")
+			}
+			required = append(required, s)
+		}
+		schema.Required = required
+	}
+
+	if rawItems, ok := raw["items"].(map[string]any); ok {
+		itemSchema, err := jsonSchemaToGenaiSchema(rawItems)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		schema.Items = itemSchema
+	}
+
+	return schema, nil
+}
+
+func jsonSchemaTypeToGenaiType(typeStr string) (genai.Type, error) {
+	switch typeStr {
+	case "string":
+		return genai.TypeString, nil
+	case "number":
+		return genai.TypeNumber, nil
+	case "integer":
+		return genai.TypeInteger, nil
+	case "boolean":
+		return genai.TypeBoolean, nil
+	case "array":
+		return genai.TypeArray, nil
+	case "object", "":
+		return genai.TypeObject, nil
+	default:
+		return genai.TypeUnspecified, fmt.Errorf("unsupported type %q", typeStr)
+	}
+}
+
+// validateAgainstSchema re-checks the model's parsed JSON output against
+// the same subset of JSON Schema used to build the request, since a
+// ResponseSchema constrains generation but doesn't guarantee the result
+// always satisfies every constraint (e.g. required fields).
+func validateAgainstSchema(data any, schema map[string]any) error {
+	typeStr, _ := schema["type"].(string)
+	if typeStr == "" {
+		typeStr = "object"
+	}
+
+	switch typeStr {
+	case "object":
+		obj, ok := data.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected an object, got %T", data)
+		}
+		if rawRequired, ok := schema["required"].([]any); ok {
+			for _, v := range rawRequired {
+				name, _ := v.(string)
+				if _, present := obj[name]; !present {
+					return fmt.Errorf("missing required field %q", name)
+				}
+			}
+		}
+		if rawProps, ok := schema["properties"].(map[string]any); ok {
+			for name, rawProp := range rawProps {
+				val, present := obj[name]
+				if !present {
+					continue
+				}
+				propSchema, ok := rawProp.(map[string]any)
+				if !ok {
+					continue
+				}
+				if err := validateAgainstSchema(val, propSchema); err != nil {
+					return fmt.Errorf("field %q: %w", name, err)
+				}
+			}
+		}
+	case "array":
+		arr, ok := data.([]any)
+		if !ok {
+			return fmt.Errorf("expected an array, got %T", data)
+		}
+		if rawItems, ok := schema["items"].(map[string]any); ok {
+			for i, item := range arr {
+				if err := validateAgainstSchema(item, rawItems); err != nil {
+					return fmt.Errorf("item %d: %w", i, err)
+				}
+			}
+		}
+	case "string":
+		s, ok := data.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %T", data)
+		}
+		if rawEnum, ok := schema["enum"].([]any); ok {
+			valid := false
+			for _, v := range rawEnum {
+				if v == s {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("value %q is not one of the allowed enum values", s)
+			}
+		}
+	case "number", "integer":
+		if _, ok := data.(float64); !ok {
+			return fmt.Errorf("expected a number, got %T", data)
+		}
+	case "boolean":
+		if _, ok := data.(bool); !ok {
+			return fmt.Errorf("expected a boolean, got %T", data)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestJSONSchemaToGenaiSchema(t *testing.T) {
+	raw := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{"type": "string"},
+			"tags": map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "string"},
+			},
+			"status": map[string]any{
+				"type": "string",
+				"enum": []any{"active", "inactive"},
+			},
+		},
+		"required": []any{"name"},
+	}
+
+	schema, err := jsonSchemaToGenaiSchema(raw)
+	if err != nil {
+		t.Fatalf("jsonSchemaToGenaiSchema returned error: %v", err)
+	}
+
+	if schema.Type != genai.TypeObject {
+		t.Fatalf("Type = %v, want TypeObject", schema.Type)
+	}
+	if got, want := schema.Required, []string{"name"}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("Required = %v, want %v", got, want)
+	}
+	if schema.Properties["name"].Type != genai.TypeString {
+		t.Fatalf("Properties[name].Type = %v, want TypeString", schema.Properties["name"].Type)
+	}
+	if schema.Properties["tags"].Items.Type != genai.TypeString {
+		t.Fatalf("Properties[tags].Items.Type = %v, want TypeString", schema.Properties["tags"].Items.Type)
+	}
+	if got, want := schema.Properties["status"].Enum, []string{"active", "inactive"}; len(got) != len(want) {
+		t.Fatalf("Properties[status].Enum = %v, want %v", got, want)
+	}
+}
+
+func TestJSONSchemaToGenaiSchemaRejectsUnsupportedKeyword(t *testing.T) {
+	raw := map[string]any{"type": "string", "oneOf": []any{}}
+	if _, err := jsonSchemaToGenaiSchema(raw); err == nil {
+		t.Fatal("expected an error for an unsupported keyword, got nil")
+	}
+}
+
+func TestJSONSchemaToGenaiSchemaRejectsUnknownType(t *testing.T) {
+	raw := map[string]any{"type": "widget"}
+	if _, err := jsonSchemaToGenaiSchema(raw); err == nil {
+		t.Fatal("expected an error for an unknown type, got nil")
+	}
+}
+
+func TestValidateAgainstSchemaObject(t *testing.T) {
+	schema := map[string]any{
+		"type":     "object",
+		"required": []any{"name"},
+		"properties": map[string]any{
+			"name": map[string]any{"type": "string"},
+		},
+	}
+
+	if err := validateAgainstSchema(map[string]any{"name": "Ada"}, schema); err != nil {
+		t.Fatalf("expected valid data to pass, got error: %v", err)
+	}
+
+	if err := validateAgainstSchema(map[string]any{}, schema); err == nil {
+		t.Fatal("expected missing required field to fail validation")
+	}
+
+	if err := validateAgainstSchema(map[string]any{"name": 5}, schema); err == nil {
+		t.Fatal("expected wrong field type to fail validation")
+	}
+}
+
+func TestValidateAgainstSchemaEnum(t *testing.T) {
+	schema := map[string]any{"type": "string", "enum": []any{"active", "inactive"}}
+
+	if err := validateAgainstSchema("active", schema); err != nil {
+		t.Fatalf("expected valid enum value to pass, got error: %v", err)
+	}
+	if err := validateAgainstSchema("archived", schema); err == nil {
+		t.Fatal("expected value outside enum to fail validation")
+	}
+}
+
+func TestValidateAgainstSchemaArray(t *testing.T) {
+	schema := map[string]any{
+		"type":  "array",
+		"items": map[string]any{"type": "number"},
+	}
+
+	if err := validateAgainstSchema([]any{1.0, 2.0}, schema); err != nil {
+		t.Fatalf("expected valid array to pass, got error: %v", err)
+	}
+	if err := validateAgainstSchema([]any{"not a number"}, schema); err == nil {
+		t.Fatal("expected wrong item type to fail validation")
+	}
+}
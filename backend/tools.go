@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// defaultMaxToolIterations bounds how many function-call round trips
+// callGeminiAPI will make before giving up, so a misbehaving tool or model
+// can't loop forever. Overridable via MAX_TOOL_ITERATIONS.
+const defaultMaxToolIterations = 5
+
+// Tool is a function the model can choose to invoke mid-conversation.
+type Tool interface {
+	Name() string
+	Description() string
+	Schema() *genai.Schema
+	Invoke(ctx context.Context, args map[string]any) (any, error)
+}
+
+// ToolRegistry holds the tools exposed to the model and dispatches
+// FunctionCall parts back to the matching Tool.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool
+}
+
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]Tool)}
+}
+
+func (r *ToolRegistry) Register(t Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[t.Name()] = t
+}
+
+func (r *ToolRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.tools)
+}
+
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// Invoke dispatches a FunctionCall by name to its registered Tool.
+func (r *ToolRegistry) Invoke(ctx context.Context, name string, args map[string]any) (any, error) {
+	tool, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %q", name)
+	}
+	return tool.Invoke(ctx, args)
+}
+
+// AsGenaiTools converts the registry into the single genai.Tool the
+// GenerativeModel expects in its Tools field.
+func (r *ToolRegistry) AsGenaiTools() []*genai.Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	decls := make([]*genai.FunctionDeclaration, 0, len(r.tools))
+	for _, t := range r.tools {
+		decls = append(decls, &genai.FunctionDeclaration{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Parameters:  t.Schema(),
+		})
+	}
+	return []*genai.Tool{{FunctionDeclarations: decls}}
+}
+
+// extractFunctionCalls pulls every FunctionCall part out of a model
+// response.
+func extractFunctionCalls(resp *genai.GenerateContentResponse) []genai.FunctionCall {
+	var calls []genai.FunctionCall
+	for _, cand := range resp.Candidates {
+		if cand.Content == nil {
+			continue
+		}
+		for _, part := range cand.Content.Parts {
+			if call, ok := part.(genai.FunctionCall); ok {
+				calls = append(calls, call)
+			}
+		}
+	}
+	return calls
+}
+
+// stripEmptyTextParts removes empty and whitespace-only genai.Text parts
+// from chat history.
+// The model sometimes emits an empty text part alongside a FunctionCall;
+// resubmitting that as-is makes the API reject the round trip with
+// "empty text parameter", so it must be filtered out before the next
+// SendMessage.
+func stripEmptyTextParts(cs *genai.ChatSession) {
+	for _, content := range cs.History {
+		filtered := content.Parts[:0]
+		for _, part := range content.Parts {
+			if text, ok := part.(genai.Text); ok && strings.TrimSpace(string(text)) == "" {
+				continue
+			}
+			filtered = append(filtered, part)
+		}
+		content.Parts = filtered
+	}
+}
+
+// getTimeTool reports the current server time. It takes no arguments.
+type getTimeTool struct{}
+
+func (getTimeTool) Name() string        { return "get_time" }
+func (getTimeTool) Description() string { return "Returns the current UTC date and time." }
+func (getTimeTool) Schema() *genai.Schema {
+	return &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{}}
+}
+
+func (getTimeTool) Invoke(ctx context.Context, args map[string]any) (any, error) {
+	return map[string]any{"utc": time.Now().UTC().Format(time.RFC3339)}, nil
+}
+
+// fetchURLTool fetches the body of an allowlisted URL. The allowlist is
+// configured via FETCH_URL_ALLOWED_HOSTS (comma-separated hostnames) to
+// guard against SSRF; requests to any other host, or to hosts that resolve
+// to a private/loopback address, are rejected.
+type fetchURLTool struct {
+	allowedHosts map[string]bool
+	httpClient   *http.Client
+}
+
+// newFetchURLTool builds the tool from FETCH_URL_ALLOWED_HOSTS. It returns
+// ok=false when no hosts are configured so the caller can leave the tool
+// unregistered entirely, rather than fail open to every public host.
+func newFetchURLTool() (tool *fetchURLTool, ok bool) {
+	allowed := make(map[string]bool)
+	for _, host := range strings.Split(os.Getenv("FETCH_URL_ALLOWED_HOSTS"), ",") {
+		host = strings.TrimSpace(host)
+		if host != "" {
+			allowed[host] = true
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, false
+	}
+	return &fetchURLTool{
+		allowedHosts: allowed,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}, true
+}
+
+func (t *fetchURLTool) Name() string { return "fetch_url" }
+func (t *fetchURLTool) Description() string {
+	return "Fetches the text content of an allowlisted URL."
+}
+func (t *fetchURLTool) Schema() *genai.Schema {
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"url": {Type: genai.TypeString, Description: "The https URL to fetch"},
+		},
+		Required: []string{"url"},
+	}
+}
+
+func (t *fetchURLTool) Invoke(ctx context.Context, args map[string]any) (any, error) {
+	raw, _ := args["url"].(string)
+	if raw == "" {
+		return nil, fmt.Errorf("url argument is required")
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %v", err)
+	}
+	if parsed.Scheme != "https" {
+		return nil, fmt.Errorf("only https URLs are allowed")
+	}
+	// Fail closed: an empty allowlist means no host is permitted, not every
+	// host.
+	if !t.allowedHosts[parsed.Hostname()] {
+		return nil, fmt.Errorf("host %q is not allowlisted", parsed.Hostname())
+	}
+	ip, err := resolveValidatedIP(parsed.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dial the address we just validated instead of handing the hostname to
+	// the default transport, which would re-resolve it and could land on a
+	// different (private) address if DNS changed between the check above
+	// and this request (DNS rebinding).
+	client := pinnedClient(parsed.Hostname(), ip, t.httpClient.Timeout)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // 1MB cap
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	return map[string]any{"status": resp.StatusCode, "body": string(body)}, nil
+}
+
+// resolveValidatedIP resolves host and returns its first address, refusing
+// the host entirely if any resolved address is loopback, link-local, or
+// private, closing the common SSRF loophole of an allowlisted hostname
+// resolving to an internal IP.
+func resolveValidatedIP(host string) (net.IP, error) {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve host: %v", err)
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() {
+			return nil, fmt.Errorf("host %q resolves to a disallowed address", host)
+		}
+	}
+	return ips[0], nil
+}
+
+// pinnedClient builds an http.Client whose transport dials ip directly
+// rather than letting the default transport re-resolve host. Without this,
+// resolveValidatedIP's check and the real request are two independent
+// lookups, leaving a TOCTOU window for DNS rebinding: a hostname can
+// resolve to a public address for the check and a private one moments
+// later for the fetch. ServerName is still set to host so TLS verification
+// checks the right certificate.
+func pinnedClient(host string, ip net.IP, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				_, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			},
+			TLSClientConfig: &tls.Config{ServerName: host},
+		},
+	}
+}
+
+// defaultToolRegistry builds the registry of tools shipped out of the box.
+// fetch_url is only registered once FETCH_URL_ALLOWED_HOSTS is configured;
+// without an allowlist it is left out entirely rather than defaulting to
+// open internet access.
+func defaultToolRegistry() *ToolRegistry {
+	registry := NewToolRegistry()
+	registry.Register(getTimeTool{})
+	if tool, ok := newFetchURLTool(); ok {
+		registry.Register(tool)
+	} else {
+		fmt.Println("fetch_url tool disabled: FETCH_URL_ALLOWED_HOSTS is not configured")
+	}
+	return registry
+}
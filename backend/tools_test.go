@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestToolRegistryDispatchesToRegisteredTool(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(getTimeTool{})
+
+	if got, want := registry.Len(), 1; got != want {
+		t.Fatalf("Len() = %d, want %d", got, want)
+	}
+
+	result, err := registry.Invoke(context.Background(), "get_time", nil)
+	if err != nil {
+		t.Fatalf("Invoke returned error: %v", err)
+	}
+	if _, ok := result.(map[string]any)["utc"]; !ok {
+		t.Fatalf("result = %v, want a map with a %q key", result, "utc")
+	}
+}
+
+func TestToolRegistryInvokeUnknownTool(t *testing.T) {
+	registry := NewToolRegistry()
+	if _, err := registry.Invoke(context.Background(), "does_not_exist", nil); err == nil {
+		t.Fatal("expected an error for an unregistered tool name")
+	}
+}
+
+func TestToolRegistryAsGenaiToolsIncludesEveryDeclaration(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(getTimeTool{})
+
+	tools := registry.AsGenaiTools()
+	if len(tools) != 1 {
+		t.Fatalf("AsGenaiTools() returned %d *genai.Tool, want 1", len(tools))
+	}
+	if got, want := len(tools[0].FunctionDeclarations), 1; got != want {
+		t.Fatalf("FunctionDeclarations has %d entries, want %d", got, want)
+	}
+	if tools[0].FunctionDeclarations[0].Name != "get_time" {
+		t.Fatalf("declaration name = %q, want %q", tools[0].FunctionDeclarations[0].Name, "get_time")
+	}
+}
+
+func TestExtractFunctionCalls(t *testing.T) {
+	resp := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{
+				Content: &genai.Content{
+					Parts: []genai.Part{
+						genai.Text("thinking..."),
+						genai.FunctionCall{Name: "get_time", Args: map[string]any{}},
+					},
+				},
+			},
+		},
+	}
+
+	calls := extractFunctionCalls(resp)
+	if len(calls) != 1 {
+		t.Fatalf("extractFunctionCalls returned %d calls, want 1", len(calls))
+	}
+	if calls[0].Name != "get_time" {
+		t.Fatalf("calls[0].Name = %q, want %q", calls[0].Name, "get_time")
+	}
+}
+
+func TestExtractFunctionCallsNoneFound(t *testing.T) {
+	resp := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{Content: &genai.Content{Parts: []genai.Part{genai.Text("just text")}}},
+		},
+	}
+	if calls := extractFunctionCalls(resp); len(calls) != 0 {
+		t.Fatalf("extractFunctionCalls returned %d calls, want 0", len(calls))
+	}
+}
+
+func TestStripEmptyTextParts(t *testing.T) {
+	cs := &genai.ChatSession{
+		History: []*genai.Content{
+			{Parts: []genai.Part{genai.Text(""), genai.FunctionCall{Name: "get_time"}}},
+			{Parts: []genai.Part{genai.Text("  "), genai.Text("keep me")}},
+		},
+	}
+
+	stripEmptyTextParts(cs)
+
+	if got := len(cs.History[0].Parts); got != 1 {
+		t.Fatalf("History[0] has %d parts, want 1 (empty text stripped)", got)
+	}
+	if _, ok := cs.History[0].Parts[0].(genai.FunctionCall); !ok {
+		t.Fatalf("History[0].Parts[0] = %v, want the FunctionCall preserved", cs.History[0].Parts[0])
+	}
+
+	if got := len(cs.History[1].Parts); got != 1 {
+		t.Fatalf("History[1] has %d parts, want 1 (blank text stripped)", got)
+	}
+	if text, ok := cs.History[1].Parts[0].(genai.Text); !ok || text != "keep me" {
+		t.Fatalf("History[1].Parts[0] = %v, want the non-empty text preserved", cs.History[1].Parts[0])
+	}
+}
+
+func TestNewFetchURLToolRequiresAllowlist(t *testing.T) {
+	t.Setenv("FETCH_URL_ALLOWED_HOSTS", "")
+	if _, ok := newFetchURLTool(); ok {
+		t.Fatal("newFetchURLTool() returned ok=true with no allowlist configured")
+	}
+}
+
+func TestNewFetchURLToolParsesAllowlist(t *testing.T) {
+	t.Setenv("FETCH_URL_ALLOWED_HOSTS", "example.com, api.example.com ,")
+	tool, ok := newFetchURLTool()
+	if !ok {
+		t.Fatal("newFetchURLTool() returned ok=false with a non-empty allowlist")
+	}
+	if !tool.allowedHosts["example.com"] || !tool.allowedHosts["api.example.com"] {
+		t.Fatalf("allowedHosts = %v, want example.com and api.example.com", tool.allowedHosts)
+	}
+	if len(tool.allowedHosts) != 2 {
+		t.Fatalf("allowedHosts has %d entries, want 2 (blank entry should be dropped)", len(tool.allowedHosts))
+	}
+}
+
+func TestFetchURLToolInvokeRejectsDisallowedHost(t *testing.T) {
+	tool := &fetchURLTool{allowedHosts: map[string]bool{"example.com": true}}
+	_, err := tool.Invoke(context.Background(), map[string]any{"url": "https://evil.example/"})
+	if err == nil {
+		t.Fatal("expected an error for a host outside the allowlist")
+	}
+}
+
+func TestFetchURLToolInvokeRejectsNonHTTPSScheme(t *testing.T) {
+	tool := &fetchURLTool{allowedHosts: map[string]bool{"example.com": true}}
+	_, err := tool.Invoke(context.Background(), map[string]any{"url": "http://example.com/"})
+	if err == nil {
+		t.Fatal("expected an error for a non-https scheme")
+	}
+}
+
+func TestFetchURLToolInvokeRequiresURLArg(t *testing.T) {
+	tool := &fetchURLTool{allowedHosts: map[string]bool{"example.com": true}}
+	if _, err := tool.Invoke(context.Background(), map[string]any{}); err == nil {
+		t.Fatal("expected an error when the url argument is missing")
+	}
+}
+
+func TestResolveValidatedIPRejectsPrivateAndLoopback(t *testing.T) {
+	cases := []string{
+		"127.0.0.1",   // loopback
+		"10.0.0.5",    // private
+		"169.254.1.1", // link-local
+	}
+	for _, host := range cases {
+		if _, err := resolveValidatedIP(host); err == nil {
+			t.Errorf("resolveValidatedIP(%q) = nil error, want a rejection", host)
+		}
+	}
+}
+
+func TestResolveValidatedIPAllowsPublicAddress(t *testing.T) {
+	ip, err := resolveValidatedIP("93.184.216.34") // a public, non-private literal
+	if err != nil {
+		t.Fatalf("resolveValidatedIP returned error for a public address: %v", err)
+	}
+	if ip.String() != "93.184.216.34" {
+		t.Fatalf("resolveValidatedIP() = %v, want 93.184.216.34", ip)
+	}
+}